@@ -4,14 +4,14 @@ import (
 	"context"
 	"encoding/json"
 	"fmt"
+	"io"
 	"log"
+	"net/http"
 	"os"
 	"os/signal"
-	"reflect"
-	"sort"
+	"strconv"
 	"sync"
 	"syscall"
-	"time"
 
 	"github.com/gin-contrib/cors"
 	"github.com/gin-gonic/gin"
@@ -20,39 +20,107 @@ import (
 	"k8s.io/client-go/kubernetes"
 	"k8s.io/client-go/rest"
 
+	"github.com/trc-ikeskin/avalok8s/internal/kubeclient"
+
 	envConfig "github.com/trc-ikeskin/avalok8s/internal/config"
 )
 
+// inClusterID is the id the in-cluster clientset is registered under, so it
+// participates in the same federated store as clusters added via /clients.
+const inClusterID = "in-cluster"
+
 var serverConfig envConfig.Config
 
-// Kubernetes client
+// Kubernetes client for the cluster this service runs in.
 var clientset *kubernetes.Clientset
 
-// Mutex for cache concurrency
-var cacheMutex sync.RWMutex
+// restConfig is the *rest.Config the in-cluster clientset was built from,
+// needed to also build a dynamic client for it.
+var restConfig *rest.Config
+
+// clients is the registry of every cluster this service streams state for.
+var clients *kubeclient.Store
+
+// subscribers is the set of connected /state clients. Every published
+// message is fanned out to all of them, rather than handed to whichever
+// one happens to win a shared channel's receive.
+var subscribers = newSubscriberRegistry()
+
+// sseMessage is a queued message for one subscriber. hasVersion is false
+// for the id-less lifecycle events published by publish (cluster-added,
+// cluster-removed); those aren't part of the per-object delta history and
+// so are never stale relative to a snapshot or replay.
+type sseMessage struct {
+	resourceVersion uint64
+	hasVersion      bool
+	data            string
+}
 
-// Cluster cache
-type ClusterState struct {
-	Nodes []NodeInfo `json:"nodes"`
+// subscriberRegistry is a thread-safe set of per-connection SSE message
+// channels. Each StreamClusterState call owns one channel for its
+// lifetime; broadcast delivers every published message to every
+// subscribed channel instead of a single shared channel's one receiver.
+type subscriberRegistry struct {
+	mu   sync.Mutex
+	subs map[chan sseMessage]struct{}
 }
 
-type NodeInfo struct {
-	Name string    `json:"name"`
-	Pods []PodInfo `json:"pods"`
+func newSubscriberRegistry() *subscriberRegistry {
+	return &subscriberRegistry{subs: make(map[chan sseMessage]struct{})}
 }
 
-type PodInfo struct {
-	Name      string `json:"name"`
-	Namespace string `json:"namespace"`
-	Status    string `json:"status"`
-	Node      string `json:"node"`
+// subscribe registers a new SSE client and returns the channel it should
+// receive published messages on. Callers must subscribe before reading any
+// snapshot or replay of existing state, so no delta published in between is
+// missed.
+func (r *subscriberRegistry) subscribe() chan sseMessage {
+	ch := make(chan sseMessage, 10)
+	r.mu.Lock()
+	r.subs[ch] = struct{}{}
+	r.mu.Unlock()
+	return ch
 }
 
-// Cached cluster state
-var clusterCache ClusterState
+// unsubscribe removes ch from the registry and closes it. Safe to call
+// even if ch was already evicted by broadcast (e.g. on disconnect racing
+// an overflow eviction).
+func (r *subscriberRegistry) unsubscribe(ch chan sseMessage) {
+	r.mu.Lock()
+	_, exists := r.subs[ch]
+	delete(r.subs, ch)
+	r.mu.Unlock()
+
+	if exists {
+		close(ch)
+	}
+}
 
-// Create event channel for cluster state changes
-var clusterStateEventChannel = make(chan string, 10)
+// broadcast fans msg out to every subscriber. SSE has no per-connection
+// flow control, so a subscriber whose buffer is already full has
+// permanently missed this message; rather than silently dropping it for
+// that one connection, broadcast evicts and closes its channel, forcing
+// StreamClusterState to return and the client's EventSource to reconnect.
+// The reconnect's Last-Event-ID then replays from the retained history (or
+// falls back to a full snapshot), so the client heals instead of staying
+// silently stale.
+func (r *subscriberRegistry) broadcast(msg sseMessage) {
+	r.mu.Lock()
+	var evicted []chan sseMessage
+	for ch := range r.subs {
+		select {
+		case ch <- msg:
+		default:
+			evicted = append(evicted, ch)
+			delete(r.subs, ch)
+		}
+	}
+	r.mu.Unlock()
+
+	for _, ch := range evicted {
+		log.Printf("subscriber buffer full; disconnecting it to force a resync")
+		close(ch)
+	}
+}
 
 func init() {
 	// creates the Kubernetes in-cluster config
@@ -60,6 +128,7 @@ func init() {
 	if err != nil {
 		panic(err.Error())
 	}
+	restConfig = config
 
 	// creates the Kubernetes ClientSet
 	clientset, err = kubernetes.NewForConfig(config)
@@ -73,123 +142,259 @@ func init() {
 	}
 }
 
-// Fetch and update cluster state
-func getClusterState() []NodeInfo {
-	log.Println("Fetching Kubernetes cluster state...")
+// publish sends an id-less SSE message (used for whole-cluster lifecycle
+// events, which aren't part of the per-object delta history) to every
+// connected subscriber.
+func publish(event string, payload interface{}) {
+	data, _ := json.Marshal(payload)
+	message := fmt.Sprintf("event: %s\ndata: %s\n\n", event, string(data))
+	subscribers.broadcast(sseMessage{data: message})
+	log.Printf("%s event sent to stream.", event)
+}
+
+// publishChange sends a single object delta as its own SSE message, tagging
+// it with an id: line so a reconnecting EventSource reports it back as
+// Last-Event-ID, to every connected subscriber.
+func publishChange(fc kubeclient.FederatedChange) {
+	data, _ := json.Marshal(fc)
+	message := fmt.Sprintf("id: %d\nevent: %s\ndata: %s\n\n", fc.ResourceVersion, fc.Kind, string(data))
+	subscribers.broadcast(sseMessage{resourceVersion: fc.ResourceVersion, hasVersion: true, data: message})
+	log.Printf("%s event for cluster %s sent to stream.", fc.Kind, fc.ClusterName)
+}
 
-	var nodesList []NodeInfo
-	nodesMap := make(map[string]NodeInfo)
+// writeFullSnapshot writes every registered cluster's state as a single SSE
+// message tagged with the current version, and returns that version.
+// Keeping the whole snapshot in one message (rather than one per cluster)
+// means a client that disconnects mid-snapshot either received it in full
+// or not at all, so a reconnect with that Last-Event-ID can never skip a
+// cluster's state permanently.
+func writeFullSnapshot(c *gin.Context) uint64 {
+	version := clients.Version()
+	data, _ := json.Marshal(clients.Snapshot())
+	fmt.Fprintf(c.Writer, "id: %d\nevent: updated\ndata: %s\n\n", version, string(data))
+	c.Writer.Flush()
+	return version
+}
 
-	// Fetch nodes
-	nodes, err := clientset.CoreV1().Nodes().List(context.TODO(), metav1.ListOptions{})
-	if err != nil {
-		log.Printf("Error fetching nodes: %v", err)
-		return nil
+// writeMessage writes a single queued message to the client's SSE stream.
+func writeMessage(c *gin.Context, msg sseMessage) error {
+	if _, err := c.Writer.Write([]byte(msg.data)); err != nil {
+		return err
 	}
+	c.Writer.Flush()
+	return nil
+}
 
-	for _, node := range nodes.Items {
-		nodesMap[node.Name] = NodeInfo{
-			Name: node.Name,
-			Pods: []PodInfo{},
+// drainBuffered non-blockingly pulls every message currently queued on ch,
+// without waiting for more to arrive.
+func drainBuffered(ch chan sseMessage) []sseMessage {
+	var pending []sseMessage
+	for {
+		select {
+		case msg, ok := <-ch:
+			if !ok {
+				return pending
+			}
+			pending = append(pending, msg)
+		default:
+			return pending
 		}
 	}
+}
 
-	// Fetch pods
-	pods, err := clientset.CoreV1().Pods("").List(context.TODO(), metav1.ListOptions{})
-	if err != nil {
-		log.Printf("Error fetching pods: %v", err)
-		return nil
+// SSE Streaming Events. A client's first connection (or a reconnect whose
+// Last-Event-ID has aged out of the retained history) gets a full snapshot
+// of every registered cluster; a reconnect with a still-retained
+// Last-Event-ID instead replays only the added/modified/removed deltas it
+// missed.
+func StreamClusterState(c *gin.Context) {
+	c.Writer.Header().Set("Content-Type", "text/event-stream")
+	c.Writer.Header().Set("Cache-Control", "no-cache")
+	c.Writer.Header().Set("Connection", "keep-alive")
+
+	// Subscribe before reading any snapshot or replay, so a delta
+	// published while that read is in flight lands in ch instead of being
+	// missed. It may also duplicate a change already covered by the
+	// snapshot/replay; snapshotVersion lets the drain below discard those.
+	ch := subscribers.subscribe()
+	defer subscribers.unsubscribe(ch)
+
+	var snapshotVersion uint64
+	replayed := false
+	if lastEventID := c.Request.Header.Get("Last-Event-ID"); lastEventID != "" {
+		if resourceVersion, err := strconv.ParseUint(lastEventID, 10, 64); err == nil {
+			if changes, ok := clients.ChangesSince(resourceVersion); ok {
+				snapshotVersion = resourceVersion
+				for _, change := range changes {
+					data, _ := json.Marshal(change)
+					fmt.Fprintf(c.Writer, "id: %d\nevent: %s\ndata: %s\n\n", change.ResourceVersion, change.Kind, string(data))
+					snapshotVersion = change.ResourceVersion
+				}
+				c.Writer.Flush()
+				replayed = true
+			} else {
+				log.Printf("Last-Event-ID %d predates the retained history; falling back to a full snapshot", resourceVersion)
+			}
+		}
+	}
+
+	if !replayed {
+		snapshotVersion = writeFullSnapshot(c)
 	}
 
-	for _, pod := range pods.Items {
-		podInfo := PodInfo{
-			Name:      pod.Name,
-			Namespace: pod.Namespace,
-			Status:    string(pod.Status.Phase),
-			Node:      pod.Spec.NodeName,
+	// Flush whatever arrived on ch while the snapshot/replay above was
+	// being built, dropping any delta it already covers.
+	for _, msg := range drainBuffered(ch) {
+		if msg.hasVersion && msg.resourceVersion <= snapshotVersion {
+			continue
 		}
+		if err := writeMessage(c, msg); err != nil {
+			log.Printf("There was an error: %v", err)
+			return
+		}
+	}
 
-		// Assign pod to the corresponding node
-		if node, exists := nodesMap[pod.Spec.NodeName]; exists {
-			node.Pods = append(node.Pods, podInfo)
-			nodesMap[pod.Spec.NodeName] = node
+	for {
+		select {
+		case msg, ok := <-ch:
+			if !ok {
+				log.Println("SSE client was disconnected to force a resync")
+				return
+			}
+			if msg.hasVersion && msg.resourceVersion <= snapshotVersion {
+				continue
+			}
+			if err := writeMessage(c, msg); err != nil {
+				log.Printf("There was an error: %v", err)
+				return
+			}
+		case <-c.Request.Context().Done():
+			log.Println("SSE client has disconnected")
+			return
 		}
 	}
+}
+
+// GetNodes returns the raw Node list for a single registered cluster.
+func GetNodes(c *gin.Context) {
+	id := c.Param("id")
+
+	entry, err := clients.Get(id)
+	if err != nil {
+		c.AbortWithStatusJSON(http.StatusNotFound, gin.H{"error": err.Error()})
+		return
+	}
+
+	nodes, err := entry.Clientset.CoreV1().Nodes().List(context.TODO(), metav1.ListOptions{})
+	if err != nil {
+		c.AbortWithStatusJSON(http.StatusBadGateway, gin.H{"error": err.Error()})
+		return
+	}
+
+	c.IndentedJSON(http.StatusOK, &nodes)
+}
 
-	// Convert the map to a slice
-	for _, node := range nodesMap {
-		nodesList = append(nodesList, node)
+// PostKubeClient registers a new cluster from a raw exec-plugin kubeconfig
+// and starts streaming its state.
+func PostKubeClient(c *gin.Context) {
+	var newRawKubeConfig kubeclient.RawKubeConfig
+
+	if err := c.BindJSON(&newRawKubeConfig); err != nil {
+		return
+	}
+
+	clientset, restConfig, err := kubeclient.CreateClientSet(newRawKubeConfig.Server, newRawKubeConfig.Command, newRawKubeConfig.Args, newRawKubeConfig.Env, newRawKubeConfig.CertificateAuthorityData)
+	if err != nil {
+		c.AbortWithStatusJSON(http.StatusBadRequest, gin.H{"error": fmt.Sprintf("failed to create Kubernetes clientset: %v", err)})
+		return
 	}
 
-	// Sort by node names
-	sort.Slice(nodesList, func(i, j int) bool {
-		return nodesList[i].Name < nodesList[j].Name
-	})
+	if err := clients.Add(newRawKubeConfig.Name, clientset, restConfig); err != nil {
+		c.AbortWithStatusJSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
 
-	log.Println("Cluster state fetched successfully.")
-	return nodesList
+	publish("cluster-added", gin.H{"clusterName": newRawKubeConfig.Name})
+	c.Status(http.StatusCreated)
 }
 
-func refreshClusterCacheAndNotify() {
-	newNodes := getClusterState()
-	if newNodes == nil {
+// PostKubeconfigClient registers one or more clusters from an uploaded
+// kubeconfig file (field "kubeconfig") or a raw kubeconfig YAML body. The
+// optional "context" form field or query param limits registration to a
+// single context; otherwise every context in the kubeconfig is registered.
+func PostKubeconfigClient(c *gin.Context) {
+	raw, err := readKubeconfigUpload(c)
+	if err != nil {
+		c.AbortWithStatusJSON(http.StatusBadRequest, gin.H{"error": err.Error()})
 		return
 	}
 
-	// Acquire read lock to compare with current cache
-	cacheMutex.RLock()
-	isSameState := reflect.DeepEqual(clusterCache.Nodes, newNodes)
-	cacheMutex.RUnlock()
+	contextName := c.PostForm("context")
+	if contextName == "" {
+		contextName = c.Query("context")
+	}
 
-	if isSameState {
-		log.Println("No changes detected in cluster state. Skipping cache update.")
+	registered, err := clients.AddFromKubeconfig(raw, contextName)
+	if err != nil {
+		c.AbortWithStatusJSON(http.StatusBadRequest, gin.H{"error": err.Error()})
 		return
 	}
 
-	// Update cache only if a real change is detected
-	cacheMutex.Lock()
-	clusterCache.Nodes = newNodes
-	cacheMutex.Unlock()
+	for _, id := range registered {
+		publish("cluster-added", gin.H{"clusterName": id})
+	}
+	c.JSON(http.StatusCreated, gin.H{"registered": registered})
+}
 
-	// Notify SSE stream about the update
-	data, _ := json.Marshal(clusterCache)
-	message := fmt.Sprintf("event: %s\ndata: %s\n\n", "updated", string(data))
+func readKubeconfigUpload(c *gin.Context) ([]byte, error) {
+	if file, err := c.FormFile("kubeconfig"); err == nil {
+		opened, err := file.Open()
+		if err != nil {
+			return nil, fmt.Errorf("failed to open uploaded kubeconfig: %v", err)
+		}
+		defer opened.Close()
+		return io.ReadAll(opened)
+	}
 
-	select {
-	case clusterStateEventChannel <- message:
-		log.Println("Cluster state updated and sent to stream.")
-	default:
-		log.Println("Event channel is full, skipping update notification.")
+	raw, err := io.ReadAll(c.Request.Body)
+	if err != nil || len(raw) == 0 {
+		return nil, fmt.Errorf("request must include a kubeconfig file upload or a kubeconfig YAML body")
 	}
+	return raw, nil
 }
 
-// SSE Streaming Events
-func StreamClusterState(c *gin.Context) {
-	c.Writer.Header().Set("Content-Type", "text/event-stream")
-	c.Writer.Header().Set("Cache-Control", "no-cache")
-	c.Writer.Header().Set("Connection", "keep-alive")
+// ListClients returns every registered cluster's id alongside its current
+// health status.
+func ListClients(c *gin.Context) {
+	c.JSON(http.StatusOK, gin.H{"clients": clients.HealthAll()})
+}
 
-	// Immediately send last known cluster state to new clients
-	cacheMutex.RLock()
-	initialState, _ := json.Marshal(clusterCache)
-	cacheMutex.RUnlock()
-	fmt.Fprintf(c.Writer, "event: updated\ndata: %s\n\n", string(initialState))
-	c.Writer.Flush()
+// DeleteKubeClient tears down a registered cluster's informers and removes
+// it from the store.
+func DeleteKubeClient(c *gin.Context) {
+	id := c.Param("id")
 
-	for {
-		select {
-		case message := <-clusterStateEventChannel:
-			_, err := c.Writer.Write([]byte(message))
-			if err != nil {
-				log.Printf("There was an error: %v", err)
-				return
-			}
-			c.Writer.Flush()
-		case <-c.Request.Context().Done():
-			log.Println("SSE client has disconnected")
-			return
-		}
+	if _, err := clients.Get(id); err != nil {
+		c.AbortWithStatusJSON(http.StatusNotFound, gin.H{"error": err.Error()})
+		return
+	}
+
+	clients.Remove(id)
+	c.Status(http.StatusNoContent)
+}
+
+// GetClientHealth returns the liveness status of a single registered
+// cluster.
+func GetClientHealth(c *gin.Context) {
+	id := c.Param("id")
+
+	health, err := clients.Health(id)
+	if err != nil {
+		c.AbortWithStatusJSON(http.StatusNotFound, gin.H{"error": err.Error()})
+		return
 	}
+
+	c.JSON(http.StatusOK, health)
 }
 
 func main() {
@@ -205,23 +410,26 @@ func main() {
 		cancel()
 	}()
 
-	// Fetch initial cluster state
-	refreshClusterCacheAndNotify()
+	clients = kubeclient.NewStore(
+		ctx,
+		serverConfig.SafetyResyncInterval,
+		serverConfig.QueryResources,
+		serverConfig.ClientHealthCheckInterval,
+		serverConfig.ClientMaxConsecutiveFailures,
+		publishChange,
+		func(id string) {
+			log.Printf("Cluster %s removed from store.", id)
+			publish("cluster-removed", gin.H{"clusterName": id})
+		},
+	)
+
+	// Register the in-cluster clientset like any other federated member.
+	if err := clients.Add(inClusterID, clientset, restConfig); err != nil {
+		log.Fatal("Error starting in-cluster watcher: ", err)
+	}
+	log.Printf("Cluster watcher started for %q, safety-net resync every %s", inClusterID, serverConfig.SafetyResyncInterval)
 
-	// Schedule updates
-	log.Printf("Scheduling queries every %d seconds...", int(serverConfig.QueryInterval.Seconds()))
-	ticker := *time.NewTicker(serverConfig.QueryInterval)
-	go func() {
-		for {
-			select {
-			case <-ctx.Done():
-				ticker.Stop()
-				return
-			case <-ticker.C:
-				refreshClusterCacheAndNotify()
-			}
-		}
-	}()
+	clients.StartHealthChecks(ctx)
 
 	// Create Gin router
 	router := gin.Default()
@@ -233,6 +441,12 @@ func main() {
 	}
 
 	router.GET("/state", StreamClusterState)
+	router.GET("/clients", ListClients)
+	router.POST("/clients", PostKubeClient)
+	router.POST("/clients/kubeconfig", PostKubeconfigClient)
+	router.DELETE("/clients/:id", DeleteKubeClient)
+	router.GET("/clients/:id/health", GetClientHealth)
+	router.GET("/client/:id/nodes", GetNodes)
 
 	// Start server
 	fmt.Println("Starting to serve API...")
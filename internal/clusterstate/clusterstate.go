@@ -0,0 +1,328 @@
+// Package clusterstate maintains an in-memory, thread-safe projection of
+// cluster Nodes, Pods, and discovered resources that is kept up to date by
+// informer event handlers rather than periodic re-listing. Every mutation
+// is hash-compared against the previous version of the object so callers
+// can tell genuine changes from no-op re-syncs.
+package clusterstate
+
+import (
+	"sort"
+	"sync"
+
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+)
+
+// NodeInfo is the projection of a corev1.Node exposed over the SSE stream.
+type NodeInfo struct {
+	Name string    `json:"name"`
+	Pods []PodInfo `json:"pods"`
+}
+
+// PodInfo is the projection of a corev1.Pod exposed over the SSE stream.
+type PodInfo struct {
+	Name      string `json:"name"`
+	Namespace string `json:"namespace"`
+	Status    string `json:"status"`
+	Node      string `json:"node"`
+}
+
+// ResourceInfo is a projection of an arbitrary discovered resource (a
+// dynamic-informer-watched GVR) exposed over the SSE stream.
+type ResourceInfo struct {
+	GVR       string                 `json:"gvr"`
+	Namespace string                 `json:"namespace,omitempty"`
+	Name      string                 `json:"name"`
+	OwnerRefs []string               `json:"ownerRefs,omitempty"`
+	Status    map[string]interface{} `json:"status,omitempty"`
+}
+
+// ClusterState is the payload published to SSE clients. ClusterName is
+// populated by the caller that owns the watcher for a given cluster; Store
+// itself is cluster-agnostic.
+type ClusterState struct {
+	ClusterName string                    `json:"clusterName,omitempty"`
+	Nodes       []NodeInfo                `json:"nodes"`
+	Resources   map[string][]ResourceInfo `json:"resources,omitempty"`
+}
+
+// ChangeKind distinguishes the three delta event types published alongside
+// full snapshots.
+type ChangeKind string
+
+const (
+	ChangeAdded    ChangeKind = "added"
+	ChangeModified ChangeKind = "modified"
+	ChangeRemoved  ChangeKind = "removed"
+)
+
+// Change is a single delta to the cluster projection, carrying only the
+// object that changed (plus its parent node key, for Pods) rather than the
+// whole cluster state.
+type Change struct {
+	Kind ChangeKind `json:"kind"`
+	// NodeKey is the node a Pod belongs to (or a Node's own name), letting
+	// a subscriber place the change without re-fetching the full tree.
+	NodeKey  string        `json:"nodeKey,omitempty"`
+	Node     *NodeInfo     `json:"node,omitempty"`
+	Pod      *PodInfo      `json:"pod,omitempty"`
+	Resource *ResourceInfo `json:"resource,omitempty"`
+}
+
+// Store is a thread-safe index of the cluster's current Node/Pod/resource
+// projection. It is mutated exclusively by informer event handlers and read
+// by the SSE handler, so every mutation reports the Change it produced (and
+// whether it produced one at all), letting callers skip notifying
+// subscribers on no-op resyncs.
+type Store struct {
+	mu         sync.RWMutex
+	nodes      map[string]NodeInfo
+	nodeHashes map[string]uint64
+	pods       map[string]PodInfo // keyed by namespace/name
+	podHashes  map[string]uint64
+
+	resources      map[string]map[string]ResourceInfo // keyed by GVR, then namespace/name
+	resourceHashes map[string]map[string]uint64
+}
+
+// NewStore creates an empty Store.
+func NewStore() *Store {
+	return &Store{
+		nodes:          make(map[string]NodeInfo),
+		nodeHashes:     make(map[string]uint64),
+		pods:           make(map[string]PodInfo),
+		podHashes:      make(map[string]uint64),
+		resources:      make(map[string]map[string]ResourceInfo),
+		resourceHashes: make(map[string]map[string]uint64),
+	}
+}
+
+func podKey(namespace, name string) string {
+	return namespace + "/" + name
+}
+
+// UpsertNode adds or updates a node in the index, keyed by its object hash.
+// It returns the resulting Change and whether the node's projection
+// actually changed.
+func (s *Store) UpsertNode(node *corev1.Node) (Change, bool) {
+	info := NodeInfo{Name: node.Name}
+	hash := hashObject(info)
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	existingHash, exists := s.nodeHashes[node.Name]
+	if exists && existingHash == hash {
+		return Change{}, false
+	}
+
+	kind := ChangeModified
+	if !exists {
+		kind = ChangeAdded
+	}
+
+	s.nodes[node.Name] = info
+	s.nodeHashes[node.Name] = hash
+
+	nodeCopy := info
+	return Change{Kind: kind, NodeKey: node.Name, Node: &nodeCopy}, true
+}
+
+// DeleteNode removes a node from the index. It returns the resulting Change
+// and whether the node was present.
+func (s *Store) DeleteNode(name string) (Change, bool) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if _, exists := s.nodes[name]; !exists {
+		return Change{}, false
+	}
+	delete(s.nodes, name)
+	delete(s.nodeHashes, name)
+
+	return Change{Kind: ChangeRemoved, NodeKey: name, Node: &NodeInfo{Name: name}}, true
+}
+
+// UpsertPod adds or updates a pod in the index, keyed by its object hash. It
+// returns the resulting Change and whether the pod's status or node
+// assignment actually changed.
+func (s *Store) UpsertPod(pod *corev1.Pod) (Change, bool) {
+	info := PodInfo{
+		Name:      pod.Name,
+		Namespace: pod.Namespace,
+		Status:    string(pod.Status.Phase),
+		Node:      pod.Spec.NodeName,
+	}
+	hash := hashObject(info)
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	key := podKey(pod.Namespace, pod.Name)
+	existingHash, exists := s.podHashes[key]
+	if exists && existingHash == hash {
+		return Change{}, false
+	}
+
+	kind := ChangeModified
+	if !exists {
+		kind = ChangeAdded
+	}
+
+	s.pods[key] = info
+	s.podHashes[key] = hash
+
+	podCopy := info
+	return Change{Kind: kind, NodeKey: info.Node, Pod: &podCopy}, true
+}
+
+// DeletePod removes a pod from the index. It returns the resulting Change
+// and whether the pod was present.
+func (s *Store) DeletePod(namespace, name string) (Change, bool) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	key := podKey(namespace, name)
+	info, exists := s.pods[key]
+	if !exists {
+		return Change{}, false
+	}
+	delete(s.pods, key)
+	delete(s.podHashes, key)
+
+	return Change{Kind: ChangeRemoved, NodeKey: info.Node, Pod: &info}, true
+}
+
+// gvrKey renders a GroupVersionResource as the string used to key
+// Store.resources and to tag ResourceInfo.GVR.
+func gvrKey(gvr schema.GroupVersionResource) string {
+	if gvr.Group == "" {
+		return gvr.Version + "/" + gvr.Resource
+	}
+	return gvr.Group + "/" + gvr.Version + "/" + gvr.Resource
+}
+
+func resourceInfoFromUnstructured(gvr schema.GroupVersionResource, obj *unstructured.Unstructured) ResourceInfo {
+	owners := obj.GetOwnerReferences()
+	ownerRefs := make([]string, 0, len(owners))
+	for _, owner := range owners {
+		ownerRefs = append(ownerRefs, owner.Kind+"/"+owner.Name)
+	}
+
+	status, _, _ := unstructured.NestedMap(obj.Object, "status")
+
+	return ResourceInfo{
+		GVR:       gvrKey(gvr),
+		Namespace: obj.GetNamespace(),
+		Name:      obj.GetName(),
+		OwnerRefs: ownerRefs,
+		Status:    status,
+	}
+}
+
+// UpsertResource adds or updates a dynamically discovered resource in the
+// index, keyed by its object hash. It returns the resulting Change and
+// whether the resource's projection actually changed.
+func (s *Store) UpsertResource(gvr schema.GroupVersionResource, obj *unstructured.Unstructured) (Change, bool) {
+	info := resourceInfoFromUnstructured(gvr, obj)
+	hash := hashObject(info)
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	key := gvrKey(gvr)
+	if s.resources[key] == nil {
+		s.resources[key] = make(map[string]ResourceInfo)
+		s.resourceHashes[key] = make(map[string]uint64)
+	}
+
+	objKey := podKey(info.Namespace, info.Name)
+	existingHash, exists := s.resourceHashes[key][objKey]
+	if exists && existingHash == hash {
+		return Change{}, false
+	}
+
+	kind := ChangeModified
+	if !exists {
+		kind = ChangeAdded
+	}
+
+	s.resources[key][objKey] = info
+	s.resourceHashes[key][objKey] = hash
+
+	infoCopy := info
+	return Change{Kind: kind, Resource: &infoCopy}, true
+}
+
+// DeleteResource removes a dynamically discovered resource from the index.
+// It returns the resulting Change and whether the resource was present.
+func (s *Store) DeleteResource(gvr schema.GroupVersionResource, namespace, name string) (Change, bool) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	key := gvrKey(gvr)
+	objects, exists := s.resources[key]
+	if !exists {
+		return Change{}, false
+	}
+
+	objKey := podKey(namespace, name)
+	info, exists := objects[objKey]
+	if !exists {
+		return Change{}, false
+	}
+	delete(objects, objKey)
+	delete(s.resourceHashes[key], objKey)
+
+	return Change{Kind: ChangeRemoved, Resource: &info}, true
+}
+
+// Snapshot rebuilds the public ClusterState from the current index,
+// assigning each pod to its node.
+func (s *Store) Snapshot() ClusterState {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	nodesByName := make(map[string]*NodeInfo, len(s.nodes))
+	for name, node := range s.nodes {
+		n := node
+		n.Pods = []PodInfo{}
+		nodesByName[name] = &n
+	}
+
+	for _, pod := range s.pods {
+		if node, exists := nodesByName[pod.Node]; exists {
+			node.Pods = append(node.Pods, pod)
+		}
+	}
+
+	nodesList := make([]NodeInfo, 0, len(nodesByName))
+	for _, node := range nodesByName {
+		nodesList = append(nodesList, *node)
+	}
+
+	sort.Slice(nodesList, func(i, j int) bool {
+		return nodesList[i].Name < nodesList[j].Name
+	})
+
+	var resources map[string][]ResourceInfo
+	if len(s.resources) > 0 {
+		resources = make(map[string][]ResourceInfo, len(s.resources))
+		for gvr, objects := range s.resources {
+			list := make([]ResourceInfo, 0, len(objects))
+			for _, info := range objects {
+				list = append(list, info)
+			}
+			sort.Slice(list, func(i, j int) bool {
+				if list[i].Namespace != list[j].Namespace {
+					return list[i].Namespace < list[j].Namespace
+				}
+				return list[i].Name < list[j].Name
+			})
+			resources[gvr] = list
+		}
+	}
+
+	return ClusterState{Nodes: nodesList, Resources: resources}
+}
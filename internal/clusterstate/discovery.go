@@ -0,0 +1,133 @@
+package clusterstate
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"time"
+
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	"k8s.io/client-go/discovery"
+	"k8s.io/client-go/dynamic"
+	"k8s.io/client-go/dynamic/dynamicinformer"
+	"k8s.io/client-go/tools/cache"
+)
+
+// DiscoverResourceGVRs resolves the GVRs a ResourceWatcher should watch.
+// query is either a comma-separated list of "version/resource" or
+// "group/version/resource" entries, or "*" (or empty) to ask the server for
+// every resource that supports both list and watch.
+func DiscoverResourceGVRs(disco discovery.DiscoveryInterface, query string) ([]schema.GroupVersionResource, error) {
+	query = strings.TrimSpace(query)
+	if query != "" && query != "*" {
+		return parseGVRQuery(query)
+	}
+
+	apiResourceLists, err := disco.ServerPreferredResources()
+	if err != nil && len(apiResourceLists) == 0 {
+		return nil, fmt.Errorf("failed to discover server resources: %v", err)
+	}
+
+	filtered := discovery.FilteredBy(discovery.SupportsAllVerbs{Verbs: []string{"list", "watch"}}, apiResourceLists)
+
+	var gvrs []schema.GroupVersionResource
+	for _, list := range filtered {
+		gv, err := schema.ParseGroupVersion(list.GroupVersion)
+		if err != nil {
+			continue
+		}
+		for _, resource := range list.APIResources {
+			gvrs = append(gvrs, gv.WithResource(resource.Name))
+		}
+	}
+	return gvrs, nil
+}
+
+func parseGVRQuery(query string) ([]schema.GroupVersionResource, error) {
+	entries := strings.Split(query, ",")
+	gvrs := make([]schema.GroupVersionResource, 0, len(entries))
+	for _, entry := range entries {
+		entry = strings.TrimSpace(entry)
+		if entry == "" {
+			continue
+		}
+
+		fields := strings.Split(entry, "/")
+		switch len(fields) {
+		case 2:
+			gvrs = append(gvrs, schema.GroupVersionResource{Version: fields[0], Resource: fields[1]})
+		case 3:
+			gvrs = append(gvrs, schema.GroupVersionResource{Group: fields[0], Version: fields[1], Resource: fields[2]})
+		default:
+			return nil, fmt.Errorf("invalid QUERY_RESOURCES entry %q, expected version/resource or group/version/resource", entry)
+		}
+	}
+	return gvrs, nil
+}
+
+// ResourceWatcher projects arbitrary discovered GVRs into a Store using
+// dynamic informers, so resources beyond the built-in Nodes/Pods projection
+// (Deployments, CRDs, ...) ride the same watch-and-publish path.
+type ResourceWatcher struct {
+	store    *Store
+	factory  dynamicinformer.DynamicSharedInformerFactory
+	onChange func(Change)
+}
+
+// NewResourceWatcher builds a ResourceWatcher over gvrs, publishing into
+// store.
+func NewResourceWatcher(store *Store, client dynamic.Interface, resync time.Duration, gvrs []schema.GroupVersionResource, onChange func(Change)) *ResourceWatcher {
+	factory := dynamicinformer.NewDynamicSharedInformerFactory(client, resync)
+
+	w := &ResourceWatcher{
+		store:    store,
+		factory:  factory,
+		onChange: onChange,
+	}
+
+	for _, gvr := range gvrs {
+		gvr := gvr
+		informer := factory.ForResource(gvr).Informer()
+		informer.AddEventHandler(cache.ResourceEventHandlerFuncs{
+			AddFunc:    func(obj interface{}) { w.handleUpsert(gvr, obj) },
+			UpdateFunc: func(_, newObj interface{}) { w.handleUpsert(gvr, newObj) },
+			DeleteFunc: func(obj interface{}) { w.handleDelete(gvr, obj) },
+		})
+	}
+
+	return w
+}
+
+// Start starts the dynamic informers and blocks until their caches have
+// synced.
+func (w *ResourceWatcher) Start(ctx context.Context) error {
+	w.factory.Start(ctx.Done())
+	w.factory.WaitForCacheSync(ctx.Done())
+	return nil
+}
+
+func (w *ResourceWatcher) handleUpsert(gvr schema.GroupVersionResource, obj interface{}) {
+	u, ok := obj.(*unstructured.Unstructured)
+	if !ok {
+		return
+	}
+	if change, changed := w.store.UpsertResource(gvr, u); changed {
+		w.onChange(change)
+	}
+}
+
+func (w *ResourceWatcher) handleDelete(gvr schema.GroupVersionResource, obj interface{}) {
+	u, ok := obj.(*unstructured.Unstructured)
+	if !ok {
+		if tombstone, isTombstone := obj.(cache.DeletedFinalStateUnknown); isTombstone {
+			u, ok = tombstone.Obj.(*unstructured.Unstructured)
+		}
+		if !ok {
+			return
+		}
+	}
+	if change, changed := w.store.DeleteResource(gvr, u.GetNamespace(), u.GetName()); changed {
+		w.onChange(change)
+	}
+}
@@ -0,0 +1,46 @@
+package clusterstate
+
+import (
+	"testing"
+
+	"k8s.io/apimachinery/pkg/runtime/schema"
+)
+
+func TestParseGVRQuerySingleEntries(t *testing.T) {
+	gvrs, err := parseGVRQuery("v1/pods, apps/v1/deployments")
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	want := []schema.GroupVersionResource{
+		{Version: "v1", Resource: "pods"},
+		{Group: "apps", Version: "v1", Resource: "deployments"},
+	}
+	if len(gvrs) != len(want) {
+		t.Fatalf("expected %d GVRs, got %d: %+v", len(want), len(gvrs), gvrs)
+	}
+	for i, gvr := range gvrs {
+		if gvr != want[i] {
+			t.Fatalf("entry %d: expected %+v, got %+v", i, want[i], gvr)
+		}
+	}
+}
+
+func TestParseGVRQuerySkipsBlankEntries(t *testing.T) {
+	gvrs, err := parseGVRQuery("v1/pods,,  ,apps/v1/deployments")
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if len(gvrs) != 2 {
+		t.Fatalf("expected blank entries to be skipped, got %d GVRs: %+v", len(gvrs), gvrs)
+	}
+}
+
+func TestParseGVRQueryInvalidEntry(t *testing.T) {
+	if _, err := parseGVRQuery("v1/pods/extra/field"); err == nil {
+		t.Fatalf("expected an error for an entry with neither 2 nor 3 fields")
+	}
+	if _, err := parseGVRQuery("justaname"); err == nil {
+		t.Fatalf("expected an error for an entry with a single field")
+	}
+}
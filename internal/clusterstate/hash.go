@@ -0,0 +1,20 @@
+package clusterstate
+
+import (
+	"encoding/json"
+	"hash/fnv"
+)
+
+// hashObject computes a stable fnv64a hash of v's canonical JSON encoding.
+// encoding/json already renders map keys in sorted order, so this is a
+// cheap, deterministic fingerprint suitable for change detection.
+func hashObject(v interface{}) uint64 {
+	data, err := json.Marshal(v)
+	if err != nil {
+		return 0
+	}
+
+	h := fnv.New64a()
+	h.Write(data)
+	return h.Sum64()
+}
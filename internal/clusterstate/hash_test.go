@@ -0,0 +1,30 @@
+package clusterstate
+
+import "testing"
+
+func TestHashObjectStableAcrossEqualValues(t *testing.T) {
+	a := NodeInfo{Name: "node-1", Pods: []PodInfo{{Name: "pod-1", Namespace: "default", Status: "Running", Node: "node-1"}}}
+	b := NodeInfo{Name: "node-1", Pods: []PodInfo{{Name: "pod-1", Namespace: "default", Status: "Running", Node: "node-1"}}}
+
+	if hashObject(a) != hashObject(b) {
+		t.Fatalf("expected equal values to hash the same")
+	}
+}
+
+func TestHashObjectStableAcrossMapKeyOrder(t *testing.T) {
+	a := map[string]interface{}{"a": 1, "b": 2, "c": 3}
+	b := map[string]interface{}{"c": 3, "a": 1, "b": 2}
+
+	if hashObject(a) != hashObject(b) {
+		t.Fatalf("expected map key order not to affect the hash")
+	}
+}
+
+func TestHashObjectDiffersOnChange(t *testing.T) {
+	a := PodInfo{Name: "pod-1", Namespace: "default", Status: "Running", Node: "node-1"}
+	b := PodInfo{Name: "pod-1", Namespace: "default", Status: "Pending", Node: "node-1"}
+
+	if hashObject(a) == hashObject(b) {
+		t.Fatalf("expected differing values to hash differently")
+	}
+}
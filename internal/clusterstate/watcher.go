@@ -0,0 +1,111 @@
+package clusterstate
+
+import (
+	"context"
+	"time"
+
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/client-go/informers"
+	"k8s.io/client-go/kubernetes"
+	"k8s.io/client-go/tools/cache"
+)
+
+// Watcher drives a Store from Node and Pod informers, invoking onChange
+// with the resulting Change whenever a handler mutates the projection. The
+// informer factory's resync period is only a safety net against missed
+// events, not the primary refresh mechanism.
+type Watcher struct {
+	store    *Store
+	factory  informers.SharedInformerFactory
+	onChange func(Change)
+}
+
+// NewWatcher builds a Watcher backed by clientset. resync configures the
+// informer factory's periodic safety-net resync.
+func NewWatcher(clientset kubernetes.Interface, resync time.Duration, onChange func(Change)) *Watcher {
+	factory := informers.NewSharedInformerFactory(clientset, resync)
+
+	w := &Watcher{
+		store:    NewStore(),
+		factory:  factory,
+		onChange: onChange,
+	}
+
+	nodeInformer := factory.Core().V1().Nodes().Informer()
+	nodeInformer.AddEventHandler(cache.ResourceEventHandlerFuncs{
+		AddFunc:    w.handleNodeEvent,
+		UpdateFunc: func(_, newObj interface{}) { w.handleNodeEvent(newObj) },
+		DeleteFunc: w.handleNodeDelete,
+	})
+
+	podInformer := factory.Core().V1().Pods().Informer()
+	podInformer.AddEventHandler(cache.ResourceEventHandlerFuncs{
+		AddFunc:    w.handlePodEvent,
+		UpdateFunc: func(_, newObj interface{}) { w.handlePodEvent(newObj) },
+		DeleteFunc: w.handlePodDelete,
+	})
+
+	return w
+}
+
+// Store returns the Watcher's underlying projection.
+func (w *Watcher) Store() *Store {
+	return w.store
+}
+
+// Start starts the informers and blocks until their caches have synced.
+func (w *Watcher) Start(ctx context.Context) error {
+	w.factory.Start(ctx.Done())
+	w.factory.WaitForCacheSync(ctx.Done())
+	return nil
+}
+
+func (w *Watcher) handleNodeEvent(obj interface{}) {
+	node, ok := obj.(*corev1.Node)
+	if !ok {
+		return
+	}
+	if change, changed := w.store.UpsertNode(node); changed {
+		w.onChange(change)
+	}
+}
+
+func (w *Watcher) handleNodeDelete(obj interface{}) {
+	node, ok := obj.(*corev1.Node)
+	if !ok {
+		if tombstone, isTombstone := obj.(cache.DeletedFinalStateUnknown); isTombstone {
+			node, ok = tombstone.Obj.(*corev1.Node)
+		}
+		if !ok {
+			return
+		}
+	}
+	if change, changed := w.store.DeleteNode(node.Name); changed {
+		w.onChange(change)
+	}
+}
+
+func (w *Watcher) handlePodEvent(obj interface{}) {
+	pod, ok := obj.(*corev1.Pod)
+	if !ok {
+		return
+	}
+	if change, changed := w.store.UpsertPod(pod); changed {
+		w.onChange(change)
+	}
+}
+
+func (w *Watcher) handlePodDelete(obj interface{}) {
+	pod, ok := obj.(*corev1.Pod)
+	if !ok {
+		if tombstone, isTombstone := obj.(cache.DeletedFinalStateUnknown); isTombstone {
+			pod, ok = tombstone.Obj.(*corev1.Pod)
+		}
+		if !ok {
+			return
+		}
+	}
+	if change, changed := w.store.DeletePod(pod.Namespace, pod.Name); changed {
+		w.onChange(change)
+	}
+}
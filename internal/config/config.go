@@ -7,7 +7,25 @@ import (
 )
 
 type Config struct {
-	QueryInterval time.Duration `env:"QUERY_INTERVAL" envDefault:"5s"`
+	// SafetyResyncInterval controls the informer factory's periodic resync,
+	// a safety net against missed watch events rather than the primary
+	// refresh mechanism.
+	SafetyResyncInterval time.Duration `env:"SAFETY_RESYNC_INTERVAL" envDefault:"5m"`
+
+	// QueryResources opts a cluster into dynamic discovery of resources
+	// beyond the built-in Nodes/Pods projection. It is a comma-separated
+	// list of "version/resource" or "group/version/resource" GVRs, or "*"
+	// to watch every list+watch-capable resource the server advertises.
+	// Empty disables dynamic resource discovery.
+	QueryResources string `env:"QUERY_RESOURCES" envDefault:""`
+
+	// ClientHealthCheckInterval controls how often each registered cluster's
+	// reachability is probed.
+	ClientHealthCheckInterval time.Duration `env:"CLIENT_HEALTH_CHECK_INTERVAL" envDefault:"30s"`
+
+	// ClientMaxConsecutiveFailures evicts a cluster from the store once its
+	// health check has failed this many times in a row. 0 disables eviction.
+	ClientMaxConsecutiveFailures int `env:"CLIENT_MAX_CONSECUTIVE_FAILURES" envDefault:"3"`
 }
 
 func NewConfig() (Config, error) {
@@ -0,0 +1,71 @@
+// Package kubeclient manages the set of Kubernetes clientsets this service
+// talks to, one per registered cluster, and keeps a cluster-state watcher
+// running for each of them.
+package kubeclient
+
+import (
+	"crypto/x509"
+	"encoding/pem"
+	"fmt"
+
+	"k8s.io/client-go/kubernetes"
+	"k8s.io/client-go/rest"
+	"k8s.io/client-go/tools/clientcmd/api"
+)
+
+// RawKubeConfig is a hand-rolled, minimal kubeconfig accepted by
+// POST /clients: a server plus an exec-plugin credential provider.
+type RawKubeConfig struct {
+	Name                     string           `json:"name"`
+	Server                   string           `json:"server"`
+	CertificateAuthorityData []byte           `json:"ca-data,omitempty"`
+	Command                  string           `json:"command"`
+	Args                     []string         `json:"args"`
+	Env                      []api.ExecEnvVar `json:"env,omitempty"`
+}
+
+// restConfigFromRaw builds a *rest.Config for an exec-plugin-authenticated
+// cluster described by a RawKubeConfig.
+func restConfigFromRaw(apiServer string, execCommand string, execArgs []string, execEnv []api.ExecEnvVar, caData []byte) (*rest.Config, error) {
+	// Validate CA data
+	if len(caData) > 0 {
+		block, _ := pem.Decode(caData)
+		if block == nil {
+			return nil, fmt.Errorf("invalid CA data: could not parse PEM")
+		}
+		if _, err := x509.ParseCertificate(block.Bytes); err != nil {
+			return nil, fmt.Errorf("invalid CA data: %v", err)
+		}
+	}
+
+	return &rest.Config{
+		Host: apiServer,
+		TLSClientConfig: rest.TLSClientConfig{
+			CAData: caData,
+		},
+		ExecProvider: &api.ExecConfig{
+			Command:         execCommand,
+			Args:            execArgs,
+			Env:             execEnv,
+			APIVersion:      "client.authentication.k8s.io/v1beta1",
+			InteractiveMode: api.NeverExecInteractiveMode,
+		},
+	}, nil
+}
+
+// CreateClientSet builds a Clientset (and the *rest.Config it was built
+// from, needed by callers that also want a dynamic client) for a cluster
+// described by the fields of a RawKubeConfig.
+func CreateClientSet(apiServer string, execCommand string, execArgs []string, execEnv []api.ExecEnvVar, caData []byte) (*kubernetes.Clientset, *rest.Config, error) {
+	config, err := restConfigFromRaw(apiServer, execCommand, execArgs, execEnv, caData)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	clientset, err := kubernetes.NewForConfig(config)
+	if err != nil {
+		return nil, nil, fmt.Errorf("failed to create Kubernetes Clientset: %v", err)
+	}
+
+	return clientset, config, nil
+}
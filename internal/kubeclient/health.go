@@ -0,0 +1,158 @@
+package kubeclient
+
+import (
+	"context"
+	"log"
+	"sync"
+	"time"
+)
+
+// healthCheckTimeout bounds a single reachability probe.
+const healthCheckTimeout = 5 * time.Second
+
+// ClientHealth is the liveness status of a single registered cluster,
+// refreshed by Store's background health checker.
+type ClientHealth struct {
+	LastCheck           time.Time     `json:"lastCheck,omitempty"`
+	LastSuccess         time.Time     `json:"lastSuccess,omitempty"`
+	LastError           string        `json:"lastError,omitempty"`
+	Latency             time.Duration `json:"latency"`
+	ConsecutiveFailures int           `json:"consecutiveFailures"`
+}
+
+// healthTracker guards a single Entry's ClientHealth so the background
+// checker and request handlers can read/update it without taking the whole
+// Store lock.
+type healthTracker struct {
+	mu     sync.RWMutex
+	health ClientHealth
+}
+
+func (h *healthTracker) snapshot() ClientHealth {
+	h.mu.RLock()
+	defer h.mu.RUnlock()
+	return h.health
+}
+
+func (h *healthTracker) recordSuccess(latency time.Duration) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+
+	now := time.Now()
+	h.health.LastCheck = now
+	h.health.LastSuccess = now
+	h.health.LastError = ""
+	h.health.Latency = latency
+	h.health.ConsecutiveFailures = 0
+}
+
+func (h *healthTracker) recordFailure(err error) int {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+
+	h.health.LastCheck = time.Now()
+	h.health.LastError = err.Error()
+	h.health.ConsecutiveFailures++
+	return h.health.ConsecutiveFailures
+}
+
+// Health returns the current health status of the cluster registered under
+// id.
+func (s *Store) Health(id string) (ClientHealth, error) {
+	entry, err := s.Get(id)
+	if err != nil {
+		return ClientHealth{}, err
+	}
+	return entry.health.snapshot(), nil
+}
+
+// HealthAll returns the current health status of every registered cluster,
+// keyed by id.
+func (s *Store) HealthAll() map[string]ClientHealth {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	statuses := make(map[string]ClientHealth, len(s.entries))
+	for id, entry := range s.entries {
+		statuses[id] = entry.health.snapshot()
+	}
+	return statuses
+}
+
+// StartHealthChecks runs a background loop that probes every registered
+// cluster's reachability via Discovery().ServerVersion(), evicting any
+// cluster whose consecutive failures reach maxConsecutiveFailures (0
+// disables eviction). It returns immediately; the loop stops when ctx is
+// done.
+func (s *Store) StartHealthChecks(ctx context.Context) {
+	if s.healthCheckInterval <= 0 {
+		return
+	}
+
+	ticker := time.NewTicker(s.healthCheckInterval)
+	go func() {
+		defer ticker.Stop()
+		for {
+			select {
+			case <-ctx.Done():
+				return
+			case <-ticker.C:
+				s.checkAll(ctx)
+			}
+		}
+	}()
+}
+
+func (s *Store) checkAll(ctx context.Context) {
+	s.mu.RLock()
+	entries := make(map[string]*Entry, len(s.entries))
+	for id, entry := range s.entries {
+		entries[id] = entry
+	}
+	s.mu.RUnlock()
+
+	for id, entry := range entries {
+		s.checkOne(ctx, id, entry)
+	}
+}
+
+// checkOne probes a single cluster. ServerVersion doesn't accept a
+// context, so the call runs on its own goroutine and the timeout is
+// enforced by racing it against checkCtx instead; a probe that hangs past
+// the timeout is recorded as a failure but its goroutine is left to finish
+// on its own.
+func (s *Store) checkOne(ctx context.Context, id string, entry *Entry) {
+	checkCtx, cancel := context.WithTimeout(ctx, healthCheckTimeout)
+	defer cancel()
+
+	type probeResult struct {
+		err     error
+		latency time.Duration
+	}
+	resultCh := make(chan probeResult, 1)
+
+	start := time.Now()
+	go func() {
+		_, err := entry.Clientset.Discovery().ServerVersion()
+		resultCh <- probeResult{err: err, latency: time.Since(start)}
+	}()
+
+	select {
+	case result := <-resultCh:
+		if result.err != nil {
+			s.handleCheckFailure(id, entry, result.err)
+			return
+		}
+		entry.health.recordSuccess(result.latency)
+	case <-checkCtx.Done():
+		s.handleCheckFailure(id, entry, checkCtx.Err())
+	}
+}
+
+func (s *Store) handleCheckFailure(id string, entry *Entry, err error) {
+	failures := entry.health.recordFailure(err)
+	if s.maxConsecutiveFailures > 0 && failures >= s.maxConsecutiveFailures {
+		log.Printf("kubeclient: evicting cluster %s after %d consecutive health-check failures: %v", id, failures, err)
+		s.Remove(id)
+	}
+}
@@ -0,0 +1,81 @@
+package kubeclient
+
+import (
+	"fmt"
+
+	"k8s.io/client-go/kubernetes"
+	"k8s.io/client-go/rest"
+	"k8s.io/client-go/tools/clientcmd"
+	clientcmdapi "k8s.io/client-go/tools/clientcmd/api"
+)
+
+// restConfigFromKubeconfig builds a *rest.Config for contextName within an
+// already-parsed kubeconfig, honoring whatever auth method that context
+// uses (token, basic auth, client cert, or exec plugin).
+func restConfigFromKubeconfig(config *clientcmdapi.Config, contextName string) (*rest.Config, error) {
+	overrides := &clientcmd.ConfigOverrides{CurrentContext: contextName}
+	clientConfig := clientcmd.NewNonInteractiveClientConfig(*config, contextName, overrides, clientcmd.NewDefaultClientConfigLoadingRules())
+
+	restConfig, err := clientConfig.ClientConfig()
+	if err != nil {
+		return nil, fmt.Errorf("failed to build client config for context %s: %v", contextName, err)
+	}
+	return restConfig, nil
+}
+
+// contextsToLoad returns contextName alone if it is set, otherwise every
+// context defined in config.
+func contextsToLoad(config *clientcmdapi.Config, contextName string) ([]string, error) {
+	if contextName != "" {
+		if _, ok := config.Contexts[contextName]; !ok {
+			return nil, fmt.Errorf("context %s not found in kubeconfig", contextName)
+		}
+		return []string{contextName}, nil
+	}
+
+	names := make([]string, 0, len(config.Contexts))
+	for name := range config.Contexts {
+		names = append(names, name)
+	}
+	return names, nil
+}
+
+// AddFromKubeconfig parses a raw kubeconfig file (or YAML body) and
+// registers a clientset for contextName, or for every context it defines if
+// contextName is empty. Each cluster is keyed by <cluster>/<context> so
+// multiple contexts pointing at different clusters can coexist. It reports
+// the ids that were successfully registered. Registered clusters run under
+// the Store's long-lived base context (see NewStore), not the request that
+// uploaded the kubeconfig.
+func (s *Store) AddFromKubeconfig(raw []byte, contextName string) ([]string, error) {
+	config, err := clientcmd.Load(raw)
+	if err != nil {
+		return nil, fmt.Errorf("failed to parse kubeconfig: %v", err)
+	}
+
+	contextNames, err := contextsToLoad(config, contextName)
+	if err != nil {
+		return nil, err
+	}
+
+	registered := make([]string, 0, len(contextNames))
+	for _, name := range contextNames {
+		restConfig, err := restConfigFromKubeconfig(config, name)
+		if err != nil {
+			return registered, err
+		}
+
+		clientset, err := kubernetes.NewForConfig(restConfig)
+		if err != nil {
+			return registered, fmt.Errorf("context %s: failed to create Kubernetes Clientset: %v", name, err)
+		}
+
+		id := fmt.Sprintf("%s/%s", config.Contexts[name].Cluster, name)
+		if err := s.Add(id, clientset, restConfig); err != nil {
+			return registered, fmt.Errorf("context %s: %v", name, err)
+		}
+		registered = append(registered, id)
+	}
+
+	return registered, nil
+}
@@ -0,0 +1,285 @@
+package kubeclient
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"sync"
+	"time"
+
+	"k8s.io/client-go/dynamic"
+	"k8s.io/client-go/kubernetes"
+	"k8s.io/client-go/rest"
+
+	"github.com/trc-ikeskin/avalok8s/internal/clusterstate"
+)
+
+// historyLimit bounds the ring buffer of FederatedChanges kept for
+// reconnecting SSE clients to replay from.
+const historyLimit = 2000
+
+// Entry is a single registered cluster: its clientset and the watchers
+// keeping its cluster-state projection up to date.
+type Entry struct {
+	ID        string
+	Clientset *kubernetes.Clientset
+	Watcher   *clusterstate.Watcher
+
+	// ResourceWatcher is nil unless the Store was configured with
+	// QueryResources and this cluster had at least one discovered GVR.
+	ResourceWatcher *clusterstate.ResourceWatcher
+
+	health *healthTracker
+	cancel context.CancelFunc
+}
+
+// FederatedChange tags a clusterstate.Change with the cluster it came from
+// and a monotonic, store-wide resource version, so an SSE stream spanning
+// every registered cluster can still expose a single, orderable
+// Last-Event-ID sequence.
+type FederatedChange struct {
+	ResourceVersion uint64 `json:"resourceVersion"`
+	ClusterName     string `json:"clusterName"`
+	clusterstate.Change
+}
+
+// Store is a thread-safe registry of clusters, each backed by a running
+// Node/Pod informer set (and, if configured, a dynamic resource informer
+// set). Adding or removing an entry starts or tears down that cluster's
+// informers; removing one also fires the onRemove callback. It also retains a bounded
+// history of every change across every cluster so reconnecting SSE clients
+// can replay deltas instead of re-fetching a full snapshot.
+type Store struct {
+	mu      sync.RWMutex
+	entries map[string]*Entry
+
+	// baseCtx is the long-lived context every entry's informers are
+	// derived from. It must never be a request context: an entry added
+	// while handling an HTTP request has to keep running long after that
+	// request returns.
+	baseCtx context.Context
+
+	resync         time.Duration
+	queryResources string
+	onChange       func(change FederatedChange)
+	onRemove       func(id string)
+
+	healthCheckInterval    time.Duration
+	maxConsecutiveFailures int
+
+	historyMu sync.Mutex
+	version   uint64
+	history   []FederatedChange
+}
+
+// NewStore creates an empty Store. ctx is the long-lived context every
+// registered cluster's informers run under; it must outlive any single
+// request (typically the process's shutdown context), since Add is called
+// from request handlers but the informers it starts must keep running
+// after the handler returns. resync is passed through to each cluster's
+// informer factories as their safety-net resync period. queryResources
+// opts every registered cluster into dynamic discovery of resources
+// beyond Nodes/Pods (see clusterstate.DiscoverResourceGVRs); empty
+// disables it. healthCheckInterval controls how often StartHealthChecks
+// probes each cluster (0 disables health checking entirely);
+// maxConsecutiveFailures evicts a cluster once its health check has
+// failed that many times in a row (0 disables eviction). onChange is
+// invoked with every recorded change; onRemove is invoked after a
+// cluster's informers have been torn down, whether by an explicit Remove
+// or by health-check eviction.
+func NewStore(ctx context.Context, resync time.Duration, queryResources string, healthCheckInterval time.Duration, maxConsecutiveFailures int, onChange func(change FederatedChange), onRemove func(id string)) *Store {
+	return &Store{
+		entries:                make(map[string]*Entry),
+		baseCtx:                ctx,
+		resync:                 resync,
+		queryResources:         queryResources,
+		healthCheckInterval:    healthCheckInterval,
+		maxConsecutiveFailures: maxConsecutiveFailures,
+		onChange:               onChange,
+		onRemove:               onRemove,
+	}
+}
+
+// recordChange assigns the next resource version to a clusterstate.Change,
+// appends it to the bounded history ring buffer, and returns it.
+func (s *Store) recordChange(id string, change clusterstate.Change) FederatedChange {
+	s.historyMu.Lock()
+	defer s.historyMu.Unlock()
+
+	s.version++
+	fc := FederatedChange{ResourceVersion: s.version, ClusterName: id, Change: change}
+
+	s.history = append(s.history, fc)
+	if len(s.history) > historyLimit {
+		s.history = s.history[len(s.history)-historyLimit:]
+	}
+
+	return fc
+}
+
+// Version returns the current store-wide resource version.
+func (s *Store) Version() uint64 {
+	s.historyMu.Lock()
+	defer s.historyMu.Unlock()
+	return s.version
+}
+
+// ChangesSince returns every change recorded after resourceVersion. ok is
+// false when a change after resourceVersion has already been evicted from
+// the ring buffer (resourceVersion is more than one below the oldest
+// retained entry) or when resourceVersion is ahead of the current version,
+// meaning the caller must fall back to sending a full snapshot.
+func (s *Store) ChangesSince(resourceVersion uint64) (changes []FederatedChange, ok bool) {
+	s.historyMu.Lock()
+	defer s.historyMu.Unlock()
+
+	if len(s.history) == 0 {
+		return nil, resourceVersion == s.version
+	}
+	if resourceVersion > s.version {
+		return nil, false
+	}
+	if resourceVersion < s.history[0].ResourceVersion-1 {
+		return nil, false
+	}
+
+	for _, change := range s.history {
+		if change.ResourceVersion > resourceVersion {
+			changes = append(changes, change)
+		}
+	}
+	return changes, true
+}
+
+// Add registers a clientset under id, starting its Node/Pod informers (and
+// its dynamic resource informers, if configured). If id is already
+// registered, its previous entry is torn down first. The informers run
+// under the Store's long-lived base context (see NewStore), not any
+// particular caller's context, so a cluster registered from an HTTP
+// handler keeps streaming after that request returns.
+func (s *Store) Add(id string, clientset *kubernetes.Clientset, restConfig *rest.Config) error {
+	s.Remove(id)
+
+	entryCtx, cancel := context.WithCancel(s.baseCtx)
+
+	watcher := clusterstate.NewWatcher(clientset, s.resync, func(change clusterstate.Change) {
+		s.onChange(s.recordChange(id, change))
+	})
+
+	if err := watcher.Start(entryCtx); err != nil {
+		cancel()
+		return fmt.Errorf("failed to start watcher for cluster %s: %v", id, err)
+	}
+
+	var resourceWatcher *clusterstate.ResourceWatcher
+	if s.queryResources != "" {
+		resourceWatcher = s.startResourceWatcher(entryCtx, id, clientset, restConfig, watcher)
+	}
+
+	s.mu.Lock()
+	s.entries[id] = &Entry{
+		ID:              id,
+		Clientset:       clientset,
+		Watcher:         watcher,
+		ResourceWatcher: resourceWatcher,
+		health:          &healthTracker{},
+		cancel:          cancel,
+	}
+	s.mu.Unlock()
+
+	return nil
+}
+
+// startResourceWatcher discovers GVRs for a cluster and, if any are found,
+// starts a ResourceWatcher feeding the same Store as watcher. Discovery or
+// dynamic-client failures are logged and treated as "no dynamic resources
+// for this cluster" rather than failing cluster registration.
+func (s *Store) startResourceWatcher(ctx context.Context, id string, clientset *kubernetes.Clientset, restConfig *rest.Config, watcher *clusterstate.Watcher) *clusterstate.ResourceWatcher {
+	gvrs, err := clusterstate.DiscoverResourceGVRs(clientset.Discovery(), s.queryResources)
+	if err != nil {
+		log.Printf("kubeclient: resource discovery failed for cluster %s: %v", id, err)
+		return nil
+	}
+	if len(gvrs) == 0 {
+		return nil
+	}
+
+	dynClient, err := dynamic.NewForConfig(restConfig)
+	if err != nil {
+		log.Printf("kubeclient: failed to build dynamic client for cluster %s: %v", id, err)
+		return nil
+	}
+
+	resourceWatcher := clusterstate.NewResourceWatcher(watcher.Store(), dynClient, s.resync, gvrs, func(change clusterstate.Change) {
+		s.onChange(s.recordChange(id, change))
+	})
+	if err := resourceWatcher.Start(ctx); err != nil {
+		log.Printf("kubeclient: failed to start resource watcher for cluster %s: %v", id, err)
+		return nil
+	}
+
+	return resourceWatcher
+}
+
+func (s *Store) stateFor(id string, watcher *clusterstate.Watcher) clusterstate.ClusterState {
+	state := watcher.Store().Snapshot()
+	state.ClusterName = id
+	return state
+}
+
+// Remove tears down a cluster's informers and removes it from the store. It
+// is a no-op if id is not registered.
+func (s *Store) Remove(id string) {
+	s.mu.Lock()
+	entry, exists := s.entries[id]
+	if exists {
+		delete(s.entries, id)
+	}
+	s.mu.Unlock()
+
+	if !exists {
+		return
+	}
+
+	entry.cancel()
+	if s.onRemove != nil {
+		s.onRemove(id)
+	}
+}
+
+// Get returns the entry registered under id.
+func (s *Store) Get(id string) (*Entry, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	entry, exists := s.entries[id]
+	if !exists {
+		return nil, fmt.Errorf("clientset with key %s not found", id)
+	}
+	return entry, nil
+}
+
+// List returns the ids of every registered cluster.
+func (s *Store) List() []string {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	ids := make([]string, 0, len(s.entries))
+	for id := range s.entries {
+		ids = append(ids, id)
+	}
+	return ids
+}
+
+// Snapshot returns the current ClusterState for every registered cluster,
+// keyed by id.
+func (s *Store) Snapshot() map[string]clusterstate.ClusterState {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	states := make(map[string]clusterstate.ClusterState, len(s.entries))
+	for id, entry := range s.entries {
+		states[id] = s.stateFor(id, entry.Watcher)
+	}
+	return states
+}
@@ -0,0 +1,93 @@
+package kubeclient
+
+import (
+	"context"
+	"testing"
+
+	"github.com/trc-ikeskin/avalok8s/internal/clusterstate"
+)
+
+func newTestStore() *Store {
+	return NewStore(context.Background(), 0, "", 0, 0, func(FederatedChange) {}, func(string) {})
+}
+
+func recordN(s *Store, id string, n int) {
+	for i := 0; i < n; i++ {
+		s.recordChange(id, clusterstate.Change{Kind: clusterstate.ChangeAdded, NodeKey: id})
+	}
+}
+
+func TestChangesSinceReturnsOnlyLaterChanges(t *testing.T) {
+	s := newTestStore()
+	recordN(s, "a", 3)
+
+	changes, ok := s.ChangesSince(1)
+	if !ok {
+		t.Fatalf("expected ok, got false")
+	}
+	if len(changes) != 2 {
+		t.Fatalf("expected 2 changes after version 1, got %d", len(changes))
+	}
+	if changes[0].ResourceVersion != 2 || changes[1].ResourceVersion != 3 {
+		t.Fatalf("unexpected resource versions: %+v", changes)
+	}
+}
+
+func TestChangesSinceAtCurrentVersionIsEmptyAndOK(t *testing.T) {
+	s := newTestStore()
+	recordN(s, "a", 3)
+
+	changes, ok := s.ChangesSince(3)
+	if !ok {
+		t.Fatalf("expected ok for a Last-Event-ID equal to the current version")
+	}
+	if len(changes) != 0 {
+		t.Fatalf("expected no changes, got %d", len(changes))
+	}
+}
+
+func TestChangesSinceWithNoHistoryMatchesOnlyCurrentVersion(t *testing.T) {
+	s := newTestStore()
+
+	if _, ok := s.ChangesSince(0); !ok {
+		t.Fatalf("expected ok when resourceVersion matches the (zero) current version with no history")
+	}
+	if _, ok := s.ChangesSince(1); ok {
+		t.Fatalf("expected !ok when resourceVersion is ahead of the current version")
+	}
+}
+
+func TestChangesSinceOlderThanRetainedHistoryFallsBackToSnapshot(t *testing.T) {
+	s := newTestStore()
+	recordN(s, "a", historyLimit+5)
+
+	oldest := s.history[0].ResourceVersion
+	if _, ok := s.ChangesSince(oldest - 2); ok {
+		t.Fatalf("expected !ok for a Last-Event-ID older than the oldest retained change")
+	}
+
+	if _, ok := s.ChangesSince(oldest - 1); !ok {
+		t.Fatalf("expected ok for a Last-Event-ID exactly at the retained boundary")
+	}
+}
+
+func TestChangesSinceAheadOfCurrentVersionIsNotOK(t *testing.T) {
+	s := newTestStore()
+	recordN(s, "a", 3)
+
+	if _, ok := s.ChangesSince(10); ok {
+		t.Fatalf("expected !ok for a Last-Event-ID ahead of the current version")
+	}
+}
+
+func TestRecordChangeEvictsOldestBeyondHistoryLimit(t *testing.T) {
+	s := newTestStore()
+	recordN(s, "a", historyLimit+10)
+
+	if len(s.history) != historyLimit {
+		t.Fatalf("expected history capped at %d, got %d", historyLimit, len(s.history))
+	}
+	if s.history[0].ResourceVersion != 11 {
+		t.Fatalf("expected oldest retained change to be version 11, got %d", s.history[0].ResourceVersion)
+	}
+}